@@ -6,52 +6,29 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strings"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
-	"github.com/hashicorp/terraform-config-inspect/tfconfig"
-)
-
-// ModuleSourceType represents the type of module source
-type ModuleSourceType string
-
-const (
-	ModuleSourceTypeLocal     ModuleSourceType = "local"
-	ModuleSourceTypeRegistry  ModuleSourceType = "registry"
-	ModuleSourceTypeGit       ModuleSourceType = "git"
-	ModuleSourceTypeGitHub    ModuleSourceType = "github"
-	ModuleSourceTypeHTTP      ModuleSourceType = "http"
-	ModuleSourceTypeS3        ModuleSourceType = "s3"
-	ModuleSourceTypeGCS       ModuleSourceType = "gcs"
-	ModuleSourceTypeMercurial ModuleSourceType = "mercurial"
-	ModuleSourceTypeUnknown   ModuleSourceType = "unknown"
+	"github.com/takaishi/tftargets/changesource"
+	"github.com/takaishi/tftargets/crossstack"
+	"github.com/takaishi/tftargets/modmanager"
+	"github.com/takaishi/tftargets/resolver"
+	"github.com/takaishi/tftargets/sources"
 )
 
 type App struct {
 	CLI *CLI
+
+	moduleManager *modmanager.Manager
 }
 
 func New(cli *CLI) *App {
 	return &App{
-		CLI: cli,
-	}
-}
-
-func (app *App) Run(ctx context.Context) error {
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: getLogLevel(),
-	}))
-	slog.SetDefault(logger)
-
-	if err := app.listTargets(); err != nil {
-		return fmt.Errorf("failed to list targets: %w", err)
+		CLI:           cli,
+		moduleManager: modmanager.New(),
 	}
-
-	return nil
 }
 
 type Set[T comparable] map[T]struct{}
@@ -82,121 +59,105 @@ func Contains(slice []string, item string) bool {
 	return false
 }
 
-// DetectModuleSourceType detects the type of module source based on the source string
-func DetectModuleSourceType(source string) ModuleSourceType {
-	// Local path (starts with ./ or ../)
-	if strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") {
-		return ModuleSourceTypeLocal
-	}
-
-	// Git with explicit protocol
-	if strings.HasPrefix(source, "git::") {
-		return ModuleSourceTypeGit
-	}
-
-	// Mercurial with explicit protocol
-	if strings.HasPrefix(source, "hg::") {
-		return ModuleSourceTypeMercurial
-	}
-
-	// S3 bucket
-	if strings.HasPrefix(source, "s3::") {
-		return ModuleSourceTypeS3
-	}
-
-	// GCS bucket
-	if strings.HasPrefix(source, "gcs::") {
-		return ModuleSourceTypeGCS
-	}
-
-	// HTTP/HTTPS URL
-	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
-		return ModuleSourceTypeHTTP
-	}
-
-	// GitHub repository (github.com/...)
-	if strings.HasPrefix(source, "github.com/") {
-		return ModuleSourceTypeGitHub
-	}
-
-	// Git repository (git@...)
-	if strings.HasPrefix(source, "git@") {
-		return ModuleSourceTypeGit
-	}
-
-	// Terraform Registry (namespace/name/provider format)
-	// Pattern: alphanumeric characters, hyphens, underscores, and forward slashes
-	// Also supports subdirectories with // (e.g., terraform-aws-modules/iam/aws//modules/iam-account)
-	registryPattern := regexp.MustCompile(`^[a-zA-Z0-9_-]+/[a-zA-Z0-9_-]+/[a-zA-Z0-9_-]+(//.*)?$`)
-	if registryPattern.MatchString(source) {
-		return ModuleSourceTypeRegistry
+// newChangeSource builds the ChangeSource selected by app.CLI.ChangeSourceType
+// ("git", the original git-diff-based behavior, is the CLI flag's default).
+// Any other value, including an empty one, is an error.
+func (app *App) newChangeSource() (changesource.ChangeSource, error) {
+	switch app.CLI.ChangeSourceType {
+	case "git":
+		return changesource.GitChangeSource{
+			BaseDir:       app.CLI.BaseDir,
+			BaseBranch:    app.CLI.BaseBranch,
+			BaseCommitSha: app.CLI.BaseCommitSha,
+			MergeBase:     app.CLI.MergeBase,
+		}, nil
+	case "hg":
+		return changesource.HgChangeSource{
+			BaseDir: app.CLI.BaseDir,
+			BaseRev: app.CLI.BaseRev,
+		}, nil
+	case "stdin":
+		return changesource.StdinChangeSource{
+			Reader:        os.Stdin,
+			NullDelimited: app.CLI.StdinNUL,
+		}, nil
+	case "file":
+		return changesource.FileListChangeSource{
+			Path: app.CLI.ChangesFile,
+		}, nil
+	case "github":
+		return changesource.GitHubEventChangeSource{
+			EventPath:   app.CLI.GitHubEventPath,
+			GitHubToken: app.CLI.GitHubToken,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown change source %q", app.CLI.ChangeSourceType)
 	}
-
-	// If none of the above patterns match, it's unknown
-	return ModuleSourceTypeUnknown
 }
 
-func getChangedFilesFromGit(baseDir, baseBranch, baseCommitSha string) ([]string, error) {
-	cmd := exec.Command("git", "fetch", "--depth=1", "origin")
-	cmd.Dir = baseDir
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("git fetch failed: %w", err)
-	}
-
-	var diffTarget string
-	if baseCommitSha != "" {
-		diffTarget = baseCommitSha
-	} else {
-		diffTarget = fmt.Sprintf("origin/%s", baseBranch)
-	}
-
-	cmd = exec.Command("git", "diff", "--name-only", diffTarget)
-	cmd.Dir = baseDir
-	output, err := cmd.Output()
+// getModuleCalls walks dir's module calls, recursing into local modules.
+// Parsed modules are served from app.moduleManager, so repeated calls for a
+// directory whose .tf files haven't changed are cheap. When res is non-nil,
+// non-local module sources are also resolved into the local cache and
+// recursed into, and refChanged reports whether any of them were pinned to
+// a ref that moved since the last run.
+func (app *App) getModuleCalls(ctx context.Context, dir string, res *resolver.Resolver) (calls Set[string], refChanged bool, err error) {
+	module, err := app.moduleManager.Load(dir)
 	if err != nil {
-		return nil, fmt.Errorf("git diff failed: %w", err)
-	}
-
-	files := strings.Split(string(output), "\n")
-	var result []string
-	for _, file := range files {
-		if file != "" {
-			result = append(result, filepath.Join(baseDir, file))
-		}
+		return nil, false, err
 	}
-	return result, nil
-}
 
-func getModuleCalls(dir string) (Set[string], error) {
-	module, diags := tfconfig.LoadModule(dir)
-	if diags.HasErrors() {
-		return nil, fmt.Errorf("failed to load module: %v", diags)
+	calls = make(Set[string])
+	for _, path := range module.FilePaths() {
+		calls.Add(path)
 	}
 
-	calls := make(Set[string])
 	for _, mc := range module.ModuleCalls {
-		sourceType := DetectModuleSourceType(mc.Source)
+		addr, err := sources.Parse(mc.Source)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to parse module source %q: %w", mc.Source, err)
+		}
 		slog.Debug("Module source detected",
 			"module", mc.Name,
 			"source", mc.Source,
-			"type", sourceType)
+			"type", addr.Type,
+			"subdir", addr.Subdir,
+			"ref", addr.Ref,
+			"declaredIn", mc.File,
+			"line", mc.Line)
+
+		switch {
+		case addr.Type == sources.TypeLocal:
+			dependencies, nestedChanged, err := app.getModuleCalls(ctx, filepath.Join(dir, addr.Local), res)
+			if err != nil {
+				return nil, false, err
+			}
+			for _, dependency := range dependencies.ToSlice() {
+				calls.Add(dependency)
+			}
+			refChanged = refChanged || nestedChanged
 
-		// Only process local modules recursively
-		if sourceType == ModuleSourceTypeLocal {
-			dependencies, err := getModuleCalls(filepath.Join(dir, mc.Source))
+		case res != nil:
+			result, err := res.Resolve(ctx, addr)
+			if err != nil {
+				return nil, false, err
+			}
+			dependencies, nestedChanged, err := app.getModuleCalls(ctx, result.Dir, res)
 			if err != nil {
-				return nil, err
+				return nil, false, err
 			}
 			for _, dependency := range dependencies.ToSlice() {
 				calls.Add(dependency)
 			}
-			calls.Add(filepath.Join(dir, mc.Source))
-		} else {
-			// For non-local modules, just add the current directory
-			calls.Add(dir)
+			refChanged = refChanged || result.RefChanged || nestedChanged
+
+		default:
+			// Remote module resolution is disabled and the source isn't
+			// local: dir's own files are already tracked above, so a
+			// change to the calling file is still detected.
 		}
 	}
-	return calls, nil
+	return calls, refChanged, nil
 }
 
 func findTargetCandidates(searchPath string) ([]string, error) {
@@ -236,6 +197,24 @@ func findTargetCandidates(searchPath string) ([]string, error) {
 	return result, nil
 }
 
+// matchTargets returns the candidates for which at least one changed file
+// is actually part of the candidate's parsed module set - i.e. it appears
+// in one of its transitive modules' ModuleMeta.Filenames - rather than
+// merely living somewhere under one of their directories. This keeps a
+// changed .tfvars file, a generated file, or an uncalled sibling submodule
+// from falsely marking a candidate as a target.
+func matchTargets(changes []string, candidateModules map[string]Set[string]) Set[string] {
+	targets := make(Set[string])
+	for _, change := range changes {
+		for candidate, files := range candidateModules {
+			if files.Contains(change) {
+				targets.Add(candidate)
+			}
+		}
+	}
+	return targets
+}
+
 func hasTerraformBlock(body hcl.Body) bool {
 	content, _, _ := body.PartialContent(&hcl.BodySchema{
 		Blocks: []hcl.BlockHeaderSchema{
@@ -254,55 +233,69 @@ func hasTerraformBlock(body hcl.Body) bool {
 	return false
 }
 
-func (app *App) listTargets() error {
-	baseBranch := app.CLI.BaseBranch
-	baseCommitSha := app.CLI.BaseCommitSha
+func (app *App) listTargets(ctx context.Context) error {
 	baseDir := app.CLI.BaseDir
 	searchPath := app.CLI.SearchPath
 
-	slog.Debug("baseBranch", "baseBranch", baseBranch)
-	slog.Debug("baseCommitSha", "baseCommitSha", baseCommitSha)
 	slog.Debug("baseDir", "baseDir", baseDir)
 	slog.Debug("searchPath", "searchPath", searchPath)
+	slog.Debug("changeSource", "type", app.CLI.ChangeSourceType)
+
+	var res *resolver.Resolver
+	if app.CLI.ResolveRemote {
+		var err error
+		res, err = resolver.New(app.CLI.ResolverCacheDir)
+		if err != nil {
+			return err
+		}
+	}
 
 	targetCandidates, err := findTargetCandidates(filepath.Join(baseDir, searchPath))
 	if err != nil {
 		return err
 	}
 
-	changes, err := getChangedFilesFromGit(baseDir, baseBranch, baseCommitSha)
+	cs, err := app.newChangeSource()
 	if err != nil {
 		return err
 	}
-	slog.Debug("getChangedFilesFromGit", "changes", changes)
+	changes, err := cs.ChangedFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get changed files: %w", err)
+	}
+	slog.Debug("changedFiles", "changes", changes)
 
-	// First, collect all module directories for each candidate
+	// First, collect every file that's part of each candidate's module set
 	candidateModules := make(map[string]Set[string])
+	targets := make(Set[string])
 	for _, candidate := range targetCandidates {
-		calls, err := getModuleCalls(candidate)
+		calls, refChanged, err := app.getModuleCalls(ctx, candidate, res)
 		if err != nil {
 			return err
 		}
-		calls.Add(candidate)
 		candidateModules[candidate] = calls
+		if refChanged {
+			// A pinned ref/version moved even though no local file
+			// changed, so the candidate is a target on its own.
+			targets.Add(candidate)
+		}
 	}
 	slog.Debug("candidateModules", "candidateModules", candidateModules)
 
 	// Then check if any changed files are within module directories
-	targets := make(Set[string])
-	for _, change := range changes {
-		for candidate, modules := range candidateModules {
-			for module := range modules {
-				// Check if the changed file is within this module directory or its subdirectories
-				if strings.HasPrefix(change, module+string(filepath.Separator)) || change == module {
-					targets.Add(candidate)
-					break
-				}
-			}
-		}
+	for candidate := range matchTargets(changes, candidateModules) {
+		targets.Add(candidate)
 	}
 	slog.Debug("targets", "targets", targets)
 
+	if app.CLI.WithDownstream || app.CLI.GraphOutput != "" {
+		targets, err = app.expandDownstream(targetCandidates, targets)
+		if err != nil {
+			return err
+		}
+		slog.Debug("targets after downstream expansion", "targets", targets)
+	}
+
 	jsonOutput, err := json.Marshal(targets.ToSlice())
 	if err != nil {
 		return fmt.Errorf("failed to marshal paths: %w", err)
@@ -312,6 +305,46 @@ func (app *App) listTargets() error {
 	return nil
 }
 
+// expandDownstream builds the cross-stack dependency graph for candidates,
+// optionally dumps it to app.CLI.GraphOutput, and - when app.CLI.WithDownstream
+// is set - expands direct to include every stack reachable by reading a
+// direct target's outputs via terraform_remote_state (or a configured
+// equivalent).
+func (app *App) expandDownstream(candidates []string, direct Set[string]) (Set[string], error) {
+	rules, err := crossstack.LoadRules(app.CLI.CrossStackRules)
+	if err != nil {
+		return nil, err
+	}
+
+	metas := make([]*crossstack.StackMeta, 0, len(candidates))
+	for _, candidate := range candidates {
+		meta, err := crossstack.Analyze(candidate, rules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to analyze cross-stack references in %q: %w", candidate, err)
+		}
+		metas = append(metas, meta)
+	}
+
+	graph := crossstack.BuildGraph(metas, candidates, rules)
+
+	if app.CLI.GraphOutput != "" {
+		if err := graph.Write(app.CLI.GraphOutput); err != nil {
+			return nil, err
+		}
+	}
+
+	if !app.CLI.WithDownstream {
+		return direct, nil
+	}
+
+	expanded := graph.Downstream(direct)
+	result := make(Set[string], len(expanded))
+	for node := range expanded {
+		result.Add(node)
+	}
+	return result, nil
+}
+
 func getLogLevel() slog.Level {
 	level := os.Getenv("LOG_LEVEL")
 	switch level {
@@ -0,0 +1,14 @@
+// Package changesource abstracts "what files changed" away from git, so
+// tftargets can run in shallow CI clones without an origin remote, in
+// Mercurial repos, or against a diff that's already known (e.g. a GitHub
+// webhook payload) - and so it can be tested without spawning VCS
+// subprocesses at all.
+package changesource
+
+import "context"
+
+// ChangeSource reports the set of files that changed, in whatever form its
+// backend understands (a VCS diff, a precomputed list, an event payload).
+type ChangeSource interface {
+	ChangedFiles(ctx context.Context) ([]string, error)
+}
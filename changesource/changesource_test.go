@@ -0,0 +1,218 @@
+package changesource
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s failed: %v: %s", args, err, out)
+	}
+}
+
+func TestGitChangeSource(t *testing.T) {
+	remote := t.TempDir()
+	runGit(t, remote, "init", "--bare", "--initial-branch=main")
+
+	work := t.TempDir()
+	runGit(t, work, "init", "--initial-branch=main")
+	runGit(t, work, "remote", "add", "origin", remote)
+
+	if err := os.WriteFile(filepath.Join(work, "main.tf"), []byte("# initial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, work, "add", "main.tf")
+	runGit(t, work, "commit", "-m", "initial")
+	runGit(t, work, "push", "origin", "main")
+
+	if err := os.WriteFile(filepath.Join(work, "changed.tf"), []byte("# changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, work, "add", "changed.tf")
+	runGit(t, work, "commit", "-m", "add changed.tf")
+
+	src := GitChangeSource{BaseDir: work, BaseBranch: "main"}
+	files, err := src.ChangedFiles(context.Background())
+	if err != nil {
+		t.Fatalf("ChangedFiles returned error: %v", err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "changed.tf" {
+		t.Fatalf("ChangedFiles = %v, want [changed.tf]", files)
+	}
+}
+
+func TestHgChangeSource(t *testing.T) {
+	if _, err := exec.LookPath("hg"); err != nil {
+		t.Skip("hg not installed")
+	}
+
+	dir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("hg", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("hg %s failed: %v: %s", args, err, out)
+		}
+	}
+	run("init")
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte("# initial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run("add", "main.tf")
+	run("commit", "-m", "initial", "-u", "test")
+
+	if err := os.WriteFile(filepath.Join(dir, "changed.tf"), []byte("# changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run("add", "changed.tf")
+
+	src := HgChangeSource{BaseDir: dir}
+	files, err := src.ChangedFiles(context.Background())
+	if err != nil {
+		t.Fatalf("ChangedFiles returned error: %v", err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "changed.tf" {
+		t.Fatalf("ChangedFiles = %v, want [changed.tf]", files)
+	}
+}
+
+func TestStdinChangeSourceNewlineDelimited(t *testing.T) {
+	src := StdinChangeSource{Reader: strings.NewReader("foo.tf\nbar/baz.tf\n")}
+	files, err := src.ChangedFiles(context.Background())
+	if err != nil {
+		t.Fatalf("ChangedFiles returned error: %v", err)
+	}
+	want := []string{"foo.tf", "bar/baz.tf"}
+	if len(files) != len(want) || files[0] != want[0] || files[1] != want[1] {
+		t.Fatalf("ChangedFiles = %v, want %v", files, want)
+	}
+}
+
+func TestStdinChangeSourceNullDelimited(t *testing.T) {
+	src := StdinChangeSource{Reader: strings.NewReader("foo.tf\x00bar/baz.tf\x00"), NullDelimited: true}
+	files, err := src.ChangedFiles(context.Background())
+	if err != nil {
+		t.Fatalf("ChangedFiles returned error: %v", err)
+	}
+	want := []string{"foo.tf", "bar/baz.tf"}
+	if len(files) != len(want) || files[0] != want[0] || files[1] != want[1] {
+		t.Fatalf("ChangedFiles = %v, want %v", files, want)
+	}
+}
+
+func TestFileListChangeSourceJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "changes.json")
+	if err := os.WriteFile(path, []byte(`["foo.tf", "bar/baz.tf"]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	src := FileListChangeSource{Path: path}
+	files, err := src.ChangedFiles(context.Background())
+	if err != nil {
+		t.Fatalf("ChangedFiles returned error: %v", err)
+	}
+	want := []string{"foo.tf", "bar/baz.tf"}
+	if len(files) != len(want) || files[0] != want[0] || files[1] != want[1] {
+		t.Fatalf("ChangedFiles = %v, want %v", files, want)
+	}
+}
+
+func TestFileListChangeSourcePlainText(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "changes.txt")
+	if err := os.WriteFile(path, []byte("foo.tf\nbar/baz.tf\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	src := FileListChangeSource{Path: path}
+	files, err := src.ChangedFiles(context.Background())
+	if err != nil {
+		t.Fatalf("ChangedFiles returned error: %v", err)
+	}
+	want := []string{"foo.tf", "bar/baz.tf"}
+	if len(files) != len(want) || files[0] != want[0] || files[1] != want[1] {
+		t.Fatalf("ChangedFiles = %v, want %v", files, want)
+	}
+}
+
+func TestGitHubEventChangeSourcePushEvent(t *testing.T) {
+	event := map[string]any{
+		"commits": []map[string]any{
+			{"added": []string{"new.tf"}, "modified": []string{"main.tf"}, "removed": []string{}},
+			{"added": []string{}, "modified": []string{"main.tf"}, "removed": []string{"old.tf"}},
+		},
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "event.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	src := GitHubEventChangeSource{EventPath: path}
+	files, err := src.ChangedFiles(context.Background())
+	if err != nil {
+		t.Fatalf("ChangedFiles returned error: %v", err)
+	}
+
+	sort.Strings(files)
+	want := []string{"main.tf", "new.tf", "old.tf"}
+	if len(files) != len(want) {
+		t.Fatalf("ChangedFiles = %v, want %v", files, want)
+	}
+	for i := range want {
+		if files[i] != want[i] {
+			t.Fatalf("ChangedFiles = %v, want %v", files, want)
+		}
+	}
+}
+
+// TestGitHubEventChangeSourceCompareFiles covers the compare API call that
+// ChangedFiles makes for a pull_request event, by pointing githubAPIBaseURL
+// at an httptest server and calling compareFiles directly.
+func TestGitHubEventChangeSourceCompareFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Fatalf("Authorization header = %q, want %q", got, "Bearer test-token")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"files": []map[string]string{
+				{"filename": "main.tf"},
+				{"filename": "modules/vpc/main.tf"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	prevBaseURL := githubAPIBaseURL
+	githubAPIBaseURL = server.URL
+	defer func() { githubAPIBaseURL = prevBaseURL }()
+
+	src := GitHubEventChangeSource{GitHubToken: "test-token", HTTPClient: server.Client()}
+	files, err := src.compareFiles(context.Background(), "org/repo", "base-sha", "head-sha")
+	if err != nil {
+		t.Fatalf("compareFiles returned error: %v", err)
+	}
+	want := []string{"main.tf", "modules/vpc/main.tf"}
+	if len(files) != len(want) || files[0] != want[0] || files[1] != want[1] {
+		t.Fatalf("compareFiles = %v, want %v", files, want)
+	}
+}
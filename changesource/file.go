@@ -0,0 +1,35 @@
+package changesource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FileListChangeSource reads changed file paths from a file: a JSON array
+// of strings when the file parses as JSON, otherwise one path per line.
+type FileListChangeSource struct {
+	Path string
+}
+
+func (s FileListChangeSource) ChangedFiles(ctx context.Context) ([]string, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read changes file %q: %w", s.Path, err)
+	}
+
+	var files []string
+	if err := json.Unmarshal(data, &files); err == nil {
+		return files, nil
+	}
+
+	var result []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			result = append(result, line)
+		}
+	}
+	return result, nil
+}
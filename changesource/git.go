@@ -0,0 +1,75 @@
+package changesource
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GitChangeSource reproduces tftargets' original behavior: fetch the base
+// branch and diff against either an explicit commit SHA or the base
+// branch's tip. With MergeBase set, it diffs against the true fork point of
+// BaseBranch and HEAD instead of BaseBranch's current tip, which matters
+// when the base branch has moved on since the feature branch was cut.
+type GitChangeSource struct {
+	BaseDir       string
+	BaseBranch    string
+	BaseCommitSha string
+	MergeBase     bool
+}
+
+func (s GitChangeSource) ChangedFiles(ctx context.Context) ([]string, error) {
+	if err := s.run(ctx, "fetch", "--depth=1", "origin", s.BaseBranch); err != nil {
+		return nil, fmt.Errorf("git fetch failed: %w", err)
+	}
+
+	diffTarget, err := s.diffTarget(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := s.output(ctx, "diff", "--name-only", diffTarget)
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %w", err)
+	}
+
+	var result []string
+	for _, file := range strings.Split(output, "\n") {
+		if file != "" {
+			result = append(result, filepath.Join(s.BaseDir, file))
+		}
+	}
+	return result, nil
+}
+
+func (s GitChangeSource) diffTarget(ctx context.Context) (string, error) {
+	if s.BaseCommitSha != "" {
+		return s.BaseCommitSha, nil
+	}
+
+	base := fmt.Sprintf("origin/%s", s.BaseBranch)
+	if !s.MergeBase {
+		return base, nil
+	}
+
+	mergeBase, err := s.output(ctx, "merge-base", base, "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to find merge base with %q: %w", base, err)
+	}
+	return strings.TrimSpace(mergeBase), nil
+}
+
+func (s GitChangeSource) run(ctx context.Context, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = s.BaseDir
+	return cmd.Run()
+}
+
+func (s GitChangeSource) output(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = s.BaseDir
+	out, err := cmd.Output()
+	return string(out), err
+}
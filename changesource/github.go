@@ -0,0 +1,132 @@
+package changesource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// GitHubEventChangeSource reads the changed files out of a GitHub Actions
+// event payload (GITHUB_EVENT_PATH). A push event carries its changed files
+// directly in commits[].added/removed/modified; a pull_request event - the
+// far more common CI trigger - doesn't, so those are resolved via GitHub's
+// compare API between the pull request's base and head SHAs. GitHubToken,
+// when set, is sent as a bearer token so the API call also works against
+// private repositories.
+type GitHubEventChangeSource struct {
+	EventPath   string
+	GitHubToken string
+	HTTPClient  *http.Client
+}
+
+type githubEvent struct {
+	Commits []struct {
+		Added    []string `json:"added"`
+		Removed  []string `json:"removed"`
+		Modified []string `json:"modified"`
+	} `json:"commits"`
+	PullRequest *struct {
+		Base struct {
+			SHA string `json:"sha"`
+		} `json:"base"`
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	} `json:"pull_request"`
+	Repository *struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+func (s GitHubEventChangeSource) ChangedFiles(ctx context.Context) ([]string, error) {
+	data, err := os.ReadFile(s.EventPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GitHub event payload %q: %w", s.EventPath, err)
+	}
+
+	var event githubEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub event payload %q: %w", s.EventPath, err)
+	}
+
+	if event.PullRequest != nil {
+		if event.Repository == nil || event.Repository.FullName == "" {
+			return nil, fmt.Errorf("GitHub event payload %q is a pull_request event but has no repository.full_name", s.EventPath)
+		}
+		return s.compareFiles(ctx, event.Repository.FullName, event.PullRequest.Base.SHA, event.PullRequest.Head.SHA)
+	}
+
+	if len(event.Commits) == 0 {
+		return nil, fmt.Errorf("GitHub event payload %q is neither a push nor a pull_request event - tftargets doesn't know how to read changed files from it", s.EventPath)
+	}
+
+	seen := map[string]struct{}{}
+	var result []string
+	add := func(paths []string) {
+		for _, p := range paths {
+			if _, ok := seen[p]; ok {
+				continue
+			}
+			seen[p] = struct{}{}
+			result = append(result, p)
+		}
+	}
+
+	for _, commit := range event.Commits {
+		add(commit.Added)
+		add(commit.Removed)
+		add(commit.Modified)
+	}
+	return result, nil
+}
+
+// githubAPIBaseURL is a var, not a const, so tests can point compareFiles at
+// an httptest server instead of the real GitHub API.
+var githubAPIBaseURL = "https://api.github.com"
+
+// compareFiles resolves a pull request's changed files via GitHub's compare
+// API (base...head), since the pull_request event payload itself carries no
+// file list.
+func (s GitHubEventChangeSource) compareFiles(ctx context.Context, fullName, base, head string) ([]string, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/compare/%s...%s", githubAPIBaseURL, fullName, base, head)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build GitHub compare request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if s.GitHubToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.GitHubToken)
+	}
+
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach GitHub compare API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("GitHub compare API returned status %d for %s", resp.StatusCode, endpoint)
+	}
+
+	var compare struct {
+		Files []struct {
+			Filename string `json:"filename"`
+		} `json:"files"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&compare); err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub compare API response: %w", err)
+	}
+
+	result := make([]string, 0, len(compare.Files))
+	for _, f := range compare.Files {
+		result = append(result, f.Filename)
+	}
+	return result, nil
+}
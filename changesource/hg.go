@@ -0,0 +1,43 @@
+package changesource
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// HgChangeSource diffs against a Mercurial revision using `hg status`.
+type HgChangeSource struct {
+	BaseDir string
+	BaseRev string
+}
+
+func (s HgChangeSource) ChangedFiles(ctx context.Context) ([]string, error) {
+	args := []string{"status"}
+	if s.BaseRev != "" {
+		args = append(args, "--rev", s.BaseRev)
+	}
+
+	cmd := exec.CommandContext(ctx, "hg", args...)
+	cmd.Dir = s.BaseDir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("hg status failed: %w", err)
+	}
+
+	var result []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line == "" {
+			continue
+		}
+		// `hg status` lines look like "M path/to/file.tf".
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		result = append(result, filepath.Join(s.BaseDir, fields[1]))
+	}
+	return result, nil
+}
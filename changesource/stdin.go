@@ -0,0 +1,49 @@
+package changesource
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+)
+
+// StdinChangeSource reads a list of changed file paths from Reader,
+// delimited by newlines or, with NullDelimited set, NUL bytes (so paths
+// containing newlines round-trip safely, matching `git diff -z` / `find -print0`).
+type StdinChangeSource struct {
+	Reader        io.Reader
+	NullDelimited bool
+}
+
+func (s StdinChangeSource) ChangedFiles(ctx context.Context) ([]string, error) {
+	scanner := bufio.NewScanner(s.Reader)
+	if s.NullDelimited {
+		scanner.Split(splitNull)
+	}
+
+	var result []string
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			result = append(result, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read changed files from stdin: %w", err)
+	}
+	return result, nil
+}
+
+func splitNull(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	for i, b := range data {
+		if b == 0 {
+			return i + 1, data[:i], nil
+		}
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -3,6 +3,8 @@ package tftargets
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"os"
 
 	"github.com/alecthomas/kong"
 )
@@ -11,15 +13,45 @@ var Version = "dev"
 var Revision = "HEAD"
 
 type GlobalOptions struct {
-	BaseBranch  string `help:"Base branch for comparison" default:"main"`
-	BaseDir     string `help:"Base directory" default:"."`
-	SearchPath  string `help:"Search path" default:"."`
-	BaseTargets string `help:"Base targets (JSON array)"`
+	BaseBranch    string `help:"Base branch for comparison" default:"main"`
+	BaseDir       string `help:"Base directory" default:"."`
+	SearchPath    string `help:"Search path" default:"."`
+	BaseTargets   string `help:"Base targets (JSON array)"`
+	BaseCommitSha string `help:"Base commit SHA to diff against, overriding base-branch (change-source=git)" name:"base-commit-sha"`
+
+	ResolveRemote    bool   `help:"Follow non-local module sources (git/registry/etc.) and treat changes inside them as targets" name:"resolve-remote"`
+	ResolverCacheDir string `help:"Directory used to cache resolved remote modules" default:".tftargets-cache" name:"resolver-cache-dir"`
+
+	WithDownstream  bool   `help:"Expand targets to stacks that read a target's outputs via terraform_remote_state and similar data sources" name:"with-downstream"`
+	CrossStackRules string `help:"Path to a JSON file of cross-stack matching rules (see crossstack.Rule)" name:"cross-stack-rules"`
+	GraphOutput     string `help:"Write the cross-stack dependency graph to this path as JSON or, with a .dot extension, Graphviz DOT" name:"graph-output"`
+
+	ChangeSourceType string `help:"Where to read the list of changed files from" enum:"git,hg,stdin,file,github" default:"git" name:"change-source"`
+	MergeBase        bool   `help:"Diff against the merge-base of base-branch and HEAD instead of its tip (change-source=git)" name:"merge-base"`
+	BaseRev          string `help:"Revision to diff against (change-source=hg)" name:"base-rev"`
+	StdinNUL         bool   `help:"Treat stdin as NUL-delimited instead of newline-delimited (change-source=stdin)" name:"stdin-nul"`
+	ChangesFile      string `help:"Path to a JSON array or newline-delimited file of changed paths (change-source=file)" name:"changes-file"`
+	GitHubEventPath  string `help:"Path to the GitHub Actions event payload (change-source=github)" env:"GITHUB_EVENT_PATH" name:"github-event-path"`
+	GitHubToken      string `help:"Token used to authenticate to the GitHub API when resolving a pull_request event's changed files (change-source=github)" env:"GITHUB_TOKEN" name:"github-token"`
 }
 
 type CLI struct {
 	Version        VersionFlag `name:"version" help:"show version"`
 	*GlobalOptions `embed:""`
+
+	List  ListCmd  `cmd:"" default:"1" help:"List target directories affected by changed files (default)"`
+	Watch WatchCmd `cmd:"" help:"Watch for filesystem changes and stream target sets as newline-delimited JSON"`
+}
+
+// ListCmd is the default command: a one-shot diff-driven listing of target
+// directories, i.e. the tool's original (pre-watch) behavior.
+type ListCmd struct{}
+
+func (l *ListCmd) Run(app *App, ctx context.Context) error {
+	if err := app.listTargets(ctx); err != nil {
+		return fmt.Errorf("failed to list targets: %w", err)
+	}
+	return nil
 }
 
 type VersionFlag string
@@ -40,11 +72,18 @@ func RunCLI(ctx context.Context, args []string) error {
 	if err != nil {
 		return fmt.Errorf("error creating CLI parser: %w", err)
 	}
-	_, err = parser.Parse(args)
+	kctx, err := parser.Parse(args)
 	if err != nil {
 		fmt.Printf("error parsing CLI: %v\n", err)
 		return fmt.Errorf("error parsing CLI: %w", err)
 	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: getLogLevel(),
+	}))
+	slog.SetDefault(logger)
+
 	app := New(&cli)
-	return app.Run(ctx)
+	kctx.BindTo(ctx, (*context.Context)(nil))
+	return kctx.Run(app)
 }
@@ -0,0 +1,75 @@
+package tftargets
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRunCLIListsChangedTarget is an end-to-end smoke test: it runs RunCLI
+// exactly as main() does, against a real on-disk module and a
+// change-source=file input, and checks the target it prints. This is the
+// level a Kong wiring mistake (e.g. a DI binding Run methods can't resolve)
+// surfaces at, since unit tests against App's methods never go through
+// RunCLI's kong.New/Parse/Run path at all.
+func TestRunCLIListsChangedTarget(t *testing.T) {
+	dir := t.TempDir()
+	moduleDir := filepath.Join(dir, "main")
+	if err := os.MkdirAll(moduleDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(moduleDir, "main.tf"), []byte(`terraform {
+  required_version = ">= 1.0"
+}
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	changesFile := filepath.Join(dir, "changes.json")
+	changes, err := json.Marshal([]string{filepath.Join("main", "main.tf")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(changesFile, changes, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	t.Cleanup(func() { os.Stdout = stdout })
+
+	runErr := RunCLI(context.Background(), []string{"--change-source=file", "--changes-file=changes.json"})
+
+	w.Close()
+	out, readErr := io.ReadAll(r)
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	if runErr != nil {
+		t.Fatalf("RunCLI returned error: %v (output: %s)", runErr, out)
+	}
+
+	var targets []string
+	if err := json.Unmarshal(out, &targets); err != nil {
+		t.Fatalf("failed to parse RunCLI output %q: %v", out, err)
+	}
+	if len(targets) != 1 || targets[0] != "main" {
+		t.Fatalf("targets = %v, want [main]", targets)
+	}
+}
@@ -0,0 +1,190 @@
+// Package crossstack analyzes cross-stack dependencies expressed through
+// terraform_remote_state (and similarly-shaped) data sources, so that a
+// change to stack A's outputs can mark a stack B that reads them as a
+// target even though B's own files never changed.
+package crossstack
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// RemoteStateRef is a single cross-stack reader data source found in a
+// stack, e.g. `data "terraform_remote_state" "vpc" { backend = "s3"; config = {...} }`.
+type RemoteStateRef struct {
+	// Type is the data source type, e.g. "terraform_remote_state".
+	Type string
+	// Name is the data source's local name.
+	Name string
+	// Backend is the value of the "backend" attribute, if present.
+	Backend string
+	// Config holds the flattened string values of the "config" attribute.
+	Config map[string]string
+}
+
+// StackMeta is everything crossstack knows about a single stack directory.
+type StackMeta struct {
+	Dir          string
+	Outputs      []string
+	RemoteStates []RemoteStateRef
+}
+
+// defaultDataTypes are the data source types treated as cross-stack
+// readers when a Rule does not say otherwise.
+var defaultDataTypes = []string{"terraform_remote_state"}
+
+// Analyze parses every .tf file in dir and extracts its declared outputs and
+// any cross-stack reader data sources, using the data types referenced by
+// rules (falling back to terraform_remote_state when rules is empty).
+func Analyze(dir string, rules []Rule) (*StackMeta, error) {
+	meta := &StackMeta{Dir: dir}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list .tf files in %q: %w", dir, err)
+	}
+
+	dataTypes := dataTypesFromRules(rules)
+
+	parser := hclparse.NewParser()
+	for _, path := range files {
+		file, diags := parser.ParseHCLFile(path)
+		if diags.HasErrors() {
+			continue
+		}
+
+		content, _, _ := file.Body.PartialContent(&hcl.BodySchema{
+			Blocks: []hcl.BlockHeaderSchema{
+				{Type: "output", LabelNames: []string{"name"}},
+				{Type: "data", LabelNames: []string{"type", "name"}},
+			},
+		})
+
+		for _, block := range content.Blocks {
+			switch block.Type {
+			case "output":
+				meta.Outputs = append(meta.Outputs, block.Labels[0])
+			case "data":
+				dataType := block.Labels[0]
+				if !Contains(dataTypes, dataType) {
+					continue
+				}
+				ref, err := parseRemoteStateBlock(block)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse %s %q in %s: %w", dataType, block.Labels[1], path, err)
+				}
+				meta.RemoteStates = append(meta.RemoteStates, ref)
+			}
+		}
+	}
+
+	return meta, nil
+}
+
+// parseRemoteStateBlock extracts the "backend" and "config" attributes of a
+// data block. Both are evaluated without an hcl.EvalContext, so only
+// literal values (no variable references) are captured - which covers the
+// common case of a hard-coded backend config.
+func parseRemoteStateBlock(block *hcl.Block) (RemoteStateRef, error) {
+	ref := RemoteStateRef{
+		Type:   block.Labels[0],
+		Name:   block.Labels[1],
+		Config: map[string]string{},
+	}
+
+	content, _, _ := block.Body.PartialContent(&hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "backend"},
+			{Name: "config"},
+		},
+	})
+
+	if attr, ok := content.Attributes["backend"]; ok {
+		if v, diags := attr.Expr.Value(nil); !diags.HasErrors() && v.Type() == cty.String {
+			ref.Backend = v.AsString()
+		}
+	}
+
+	if attr, ok := content.Attributes["config"]; ok {
+		v, diags := attr.Expr.Value(nil)
+		if !diags.HasErrors() {
+			ref.Config = ctyToStringMap(v)
+		}
+	}
+
+	return ref, nil
+}
+
+// ctyToStringMap flattens an object/map cty.Value's string-ish values into a
+// plain map, skipping values that are unknown or not easily stringified.
+func ctyToStringMap(v cty.Value) map[string]string {
+	out := map[string]string{}
+	if v.IsNull() || !v.CanIterateElements() {
+		return out
+	}
+	it := v.ElementIterator()
+	for it.Next() {
+		k, ev := it.Element()
+		if !ev.IsWhollyKnown() {
+			continue
+		}
+		switch ev.Type() {
+		case cty.String:
+			out[k.AsString()] = ev.AsString()
+		case cty.Number:
+			out[k.AsString()] = ev.AsBigFloat().String()
+		case cty.Bool:
+			out[k.AsString()] = fmt.Sprintf("%t", ev.True())
+		}
+	}
+	return out
+}
+
+// Contains reports whether item is present in slice.
+func Contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
+
+func dataTypesFromRules(rules []Rule) []string {
+	if len(rules) == 0 {
+		return defaultDataTypes
+	}
+	var types []string
+	for _, r := range rules {
+		for _, t := range r.DataTypes() {
+			if !Contains(types, t) {
+				types = append(types, t)
+			}
+		}
+	}
+	return types
+}
+
+// matchesStack reports whether value appears to reference stack's directory,
+// either via a rule's named pattern or, with no pattern, by plain substring
+// containment of the stack's base name.
+func matchesStack(value string, stack string, rule Rule) bool {
+	base := filepath.Base(stack)
+	if pattern := rule.CompiledPattern(); pattern != nil {
+		match := pattern.FindStringSubmatch(value)
+		if match == nil {
+			return false
+		}
+		idx := pattern.SubexpIndex("stack")
+		if idx < 0 {
+			return strings.Contains(base, match[0]) || strings.Contains(match[0], base)
+		}
+		return match[idx] == base || strings.Contains(match[idx], base)
+	}
+	return strings.Contains(value, base)
+}
@@ -0,0 +1,126 @@
+package crossstack
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Graph is a directed graph where an edge from A to B means "B reads A",
+// i.e. a change to A can affect B.
+type Graph struct {
+	Edges map[string][]string `json:"edges"`
+}
+
+// NewGraph returns an empty Graph.
+func NewGraph() *Graph {
+	return &Graph{Edges: map[string][]string{}}
+}
+
+// AddEdge records that reader depends on source, if not already recorded.
+func (g *Graph) AddEdge(source, reader string) {
+	for _, existing := range g.Edges[source] {
+		if existing == reader {
+			return
+		}
+	}
+	g.Edges[source] = append(g.Edges[source], reader)
+}
+
+// BuildGraph inspects each stack's cross-stack reader data sources and links
+// it back to whichever candidate directory the rules say it reads from.
+func BuildGraph(metas []*StackMeta, candidates []string, rules []Rule) *Graph {
+	graph := NewGraph()
+
+	for _, meta := range metas {
+		for _, ref := range meta.RemoteStates {
+			for _, rule := range rules {
+				if !Contains(rule.DataTypes(), ref.Type) {
+					continue
+				}
+				for _, key := range rule.configKeys() {
+					value := ref.Config[key]
+					if value == "" {
+						continue
+					}
+					for _, candidate := range candidates {
+						if candidate == meta.Dir {
+							continue
+						}
+						if matchesStack(value, candidate, rule) {
+							slog.Debug("cross-stack edge detected",
+								"source", candidate, "reader", meta.Dir, "via", ref.Type, "configKey", key)
+							graph.AddEdge(candidate, meta.Dir)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return graph
+}
+
+// Downstream returns the set of nodes reachable from any node in direct by
+// following edges (source -> reader), including the nodes in direct itself.
+func (g *Graph) Downstream(direct map[string]struct{}) map[string]struct{} {
+	result := make(map[string]struct{}, len(direct))
+	queue := make([]string, 0, len(direct))
+	for node := range direct {
+		result[node] = struct{}{}
+		queue = append(queue, node)
+	}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, reader := range g.Edges[node] {
+			if _, seen := result[reader]; seen {
+				continue
+			}
+			result[reader] = struct{}{}
+			queue = append(queue, reader)
+		}
+	}
+
+	return result
+}
+
+// Write dumps the graph to path as JSON or DOT, chosen by file extension
+// (".dot" for Graphviz, anything else for JSON).
+func (g *Graph) Write(path string) error {
+	if strings.EqualFold(filepath.Ext(path), ".dot") {
+		return g.writeDOT(path)
+	}
+	return g.writeJSON(path)
+}
+
+func (g *Graph) writeJSON(path string) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cross-stack graph: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cross-stack graph to %q: %w", path, err)
+	}
+	return nil
+}
+
+func (g *Graph) writeDOT(path string) error {
+	var b strings.Builder
+	b.WriteString("digraph crossstack {\n")
+	for source, readers := range g.Edges {
+		for _, reader := range readers {
+			fmt.Fprintf(&b, "  %q -> %q;\n", source, reader)
+		}
+	}
+	b.WriteString("}\n")
+
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write cross-stack graph to %q: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package crossstack
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// Rule declares how a cross-stack data source's config should be matched
+// back to the stack it reads from. Users can declare additional rules in a
+// small JSON config file to cover readers beyond terraform_remote_state
+// (e.g. "aws_ssm_parameter", "tfe_outputs") and naming conventions that
+// don't simply embed the source stack's directory name.
+type Rule struct {
+	// Name is a human-readable label shown in debug output.
+	Name string `json:"name"`
+
+	// DataType is the data source type this rule applies to, e.g.
+	// "terraform_remote_state" or "aws_ssm_parameter". Defaults to
+	// "terraform_remote_state" when empty.
+	DataType string `json:"data_type"`
+
+	// ConfigKeys lists which keys of the "config" attribute to inspect.
+	// Defaults to ["key", "workspace", "path"] when empty.
+	ConfigKeys []string `json:"config_keys"`
+
+	// Pattern is an optional regexp matched against each config value. A
+	// named capture group "stack" is compared against candidate directory
+	// base names; with no named group the whole match is compared instead.
+	// When Pattern is empty, the raw config value is matched by plain
+	// substring containment of the candidate's base name.
+	Pattern string `json:"pattern"`
+
+	compiled *regexp.Regexp
+}
+
+func (r Rule) DataTypes() []string {
+	if r.DataType == "" {
+		return defaultDataTypes
+	}
+	return []string{r.DataType}
+}
+
+func (r Rule) configKeys() []string {
+	if len(r.ConfigKeys) == 0 {
+		return []string{"key", "workspace", "path"}
+	}
+	return r.ConfigKeys
+}
+
+// CompiledPattern returns Pattern's compiled form, compiled once up front by
+// compileRules when the Rule was produced via LoadRules/DefaultRules.
+// Returns nil when Pattern is empty or failed to compile.
+func (r Rule) CompiledPattern() *regexp.Regexp {
+	return r.compiled
+}
+
+// compileRules compiles each rule's Pattern once, so CompiledPattern - which
+// BuildGraph calls once per stack/remote-state-ref/rule/config-key - is a
+// plain field access instead of a regexp.Compile call every time.
+func compileRules(rules []Rule) []Rule {
+	for i := range rules {
+		if rules[i].Pattern == "" {
+			continue
+		}
+		if compiled, err := regexp.Compile(rules[i].Pattern); err == nil {
+			rules[i].compiled = compiled
+		}
+	}
+	return rules
+}
+
+// DefaultRules returns the built-in rule set applied when no config file is
+// given: match terraform_remote_state's "key"/"workspace"/"path" config
+// values against candidate directory names by substring containment.
+func DefaultRules() []Rule {
+	return compileRules([]Rule{{Name: "default terraform_remote_state"}})
+}
+
+// LoadRules reads a JSON array of Rule from path. An empty path returns
+// DefaultRules.
+func LoadRules(path string) ([]Rule, error) {
+	if path == "" {
+		return DefaultRules(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cross-stack rules file %q: %w", path, err)
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse cross-stack rules file %q: %w", path, err)
+	}
+	return compileRules(rules), nil
+}
@@ -0,0 +1,110 @@
+// Package modmanager caches parsed Terraform modules keyed by directory and
+// modification time, the way terraform-ls's ModuleManager caches module
+// metadata instead of reloading it on every request. It also collapses
+// concurrent loads of the same directory into a single modmeta.Load call,
+// which matters once a caller (e.g. the watch command) is re-parsing many
+// directories in parallel after a burst of filesystem events.
+package modmanager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/takaishi/tftargets/modmeta"
+)
+
+// Manager is a directory-keyed, mtime-validated cache of parsed modules.
+type Manager struct {
+	mu       sync.Mutex
+	cache    map[string]entry
+	inFlight map[string]*call
+}
+
+type entry struct {
+	mtime  int64
+	module *modmeta.ModuleMeta
+	err    error
+}
+
+type call struct {
+	wg     sync.WaitGroup
+	module *modmeta.ModuleMeta
+	err    error
+}
+
+// New returns an empty Manager.
+func New() *Manager {
+	return &Manager{
+		cache:    map[string]entry{},
+		inFlight: map[string]*call{},
+	}
+}
+
+// Load returns the parsed module for dir, reusing a cached result when the
+// directory's .tf files haven't changed since it was last loaded. Concurrent
+// calls for the same dir share a single underlying modmeta.Load call.
+func (m *Manager) Load(dir string) (*modmeta.ModuleMeta, error) {
+	mtime, err := latestTfFileMtime(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	if e, ok := m.cache[dir]; ok && e.mtime == mtime {
+		m.mu.Unlock()
+		return e.module, e.err
+	}
+	if c, ok := m.inFlight[dir]; ok {
+		m.mu.Unlock()
+		c.wg.Wait()
+		return c.module, c.err
+	}
+
+	c := &call{}
+	c.wg.Add(1)
+	m.inFlight[dir] = c
+	m.mu.Unlock()
+
+	module, loadErr := modmeta.Load(dir)
+	c.module, c.err = module, loadErr
+	c.wg.Done()
+
+	m.mu.Lock()
+	delete(m.inFlight, dir)
+	m.cache[dir] = entry{mtime: mtime, module: module, err: loadErr}
+	m.mu.Unlock()
+
+	return module, loadErr
+}
+
+// Invalidate drops dir's cached entry, forcing the next Load to re-parse it.
+func (m *Manager) Invalidate(dir string) {
+	m.mu.Lock()
+	delete(m.cache, dir)
+	m.mu.Unlock()
+}
+
+// latestTfFileMtime returns the most recent modification time, as a Unix
+// nanosecond timestamp, among dir's *.tf files. It is used instead of dir's
+// own mtime because on most platforms a directory's mtime only changes when
+// entries are added or removed, not when an existing file is edited.
+func latestTfFileMtime(dir string) (int64, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list .tf files in %q: %w", dir, err)
+	}
+
+	var latest int64
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to stat %q: %w", path, err)
+		}
+		if mtime := info.ModTime().UnixNano(); mtime > latest {
+			latest = mtime
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,100 @@
+// Package modmeta is a thin wrapper over terraform-schema's earlydecoder,
+// used in place of tfconfig.LoadModule wherever callers need to know which
+// .tf file a declaration came from. tfconfig only reports that a module has
+// a module call or a backend, not which file or line - so a changed
+// .tfvars file or an unrelated sibling submodule looks indistinguishable
+// from a change to the file that actually declares the module call.
+package modmeta
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/terraform-schema/earlydecoder"
+)
+
+// ModuleCallRef is a single `module` block, with the file and line
+// earlydecoder recorded it at when available.
+type ModuleCallRef struct {
+	Name   string
+	Source string
+	File   string
+	Line   int
+}
+
+// ModuleMeta is tftargets' projection of earlydecoder's module.Meta: the
+// files actually parsed, each module call's provenance, the providers it
+// requires, and its backend type.
+type ModuleMeta struct {
+	Dir               string
+	Filenames         []string
+	ModuleCalls       []ModuleCallRef
+	RequiredProviders []string
+	BackendType       string
+}
+
+// FilePaths returns each filename in Filenames joined with Dir.
+func (m *ModuleMeta) FilePaths() []string {
+	paths := make([]string, 0, len(m.Filenames))
+	for _, name := range m.Filenames {
+		paths = append(paths, filepath.Join(m.Dir, name))
+	}
+	return paths
+}
+
+// Load parses every .tf file in dir and decodes it with earlydecoder.
+func Load(dir string) (*ModuleMeta, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list .tf files in %q: %w", dir, err)
+	}
+
+	parser := hclparse.NewParser()
+	files := make(map[string]*hcl.File, len(paths))
+	for _, path := range paths {
+		file, diags := parser.ParseHCLFile(path)
+		if diags.HasErrors() {
+			continue
+		}
+		files[filepath.Base(path)] = file
+	}
+
+	meta, diags := earlydecoder.LoadModule(dir, files)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to decode module %q: %w", dir, diags)
+	}
+
+	result := &ModuleMeta{Dir: dir, Filenames: meta.Filenames}
+	if result.Filenames == nil {
+		// Fall back to the files we actually parsed when this build of
+		// earlydecoder predates per-module filename tracking.
+		for name := range files {
+			result.Filenames = append(result.Filenames, name)
+		}
+	}
+
+	for name, call := range meta.ModuleCalls {
+		// RawSourceAddr, not SourceAddr, so debug output shows the literal
+		// source string the user wrote rather than earlydecoder's
+		// normalized form (e.g. a registry source with the default host
+		// prepended, or GitHub shorthand rewritten to a git:: URL).
+		ref := ModuleCallRef{Name: name, Source: call.RawSourceAddr}
+		if call.RangePtr != nil {
+			ref.File = filepath.Base(call.RangePtr.Filename)
+			ref.Line = call.RangePtr.Start.Line
+		}
+		result.ModuleCalls = append(result.ModuleCalls, ref)
+	}
+
+	for providerAddr := range meta.ProviderRequirements {
+		result.RequiredProviders = append(result.RequiredProviders, fmt.Sprintf("%v", providerAddr))
+	}
+
+	if meta.Backend != nil {
+		result.BackendType = meta.Backend.Type
+	}
+
+	return result, nil
+}
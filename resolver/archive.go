@@ -0,0 +1,152 @@
+package resolver
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// downloadArchive fetches transport over HTTP(S) and extracts it into dir.
+// It supports the archive formats go-getter's HTTP getter commonly serves:
+// .tar.gz/.tgz and .zip. Anything else is written out as a single file,
+// since not every HTTP module source is actually archived.
+func downloadArchive(ctx context.Context, transport, dir string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, transport, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build archive request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download %q: %w", transport, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("download of %q returned status %d", transport, resp.StatusCode)
+	}
+
+	switch {
+	case strings.HasSuffix(transport, ".tar.gz") || strings.HasSuffix(transport, ".tgz"):
+		return extractTarGz(resp.Body, dir)
+	case strings.HasSuffix(transport, ".zip"):
+		return extractZip(resp.Body, dir)
+	default:
+		return writeFile(resp.Body, filepath.Join(dir, filepath.Base(transport)))
+	}
+}
+
+func extractTarGz(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to open gzip stream: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar entry: %w", err)
+		}
+
+		target, err := safeJoin(dir, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := writeFile(tr, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func extractZip(r io.Reader, dir string) error {
+	tmp, err := os.CreateTemp("", "tftargets-archive-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file for zip download: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		return fmt.Errorf("failed to buffer zip download: %w", err)
+	}
+
+	zr, err := zip.OpenReader(tmp.Name())
+	if err != nil {
+		return fmt.Errorf("failed to open zip archive: %w", err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		target, err := safeJoin(dir, f.Name)
+		if err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open zip entry %q: %w", f.Name, err)
+		}
+		err = writeFile(rc, target)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// safeJoin joins name onto dir and rejects the result if it would land
+// outside dir, e.g. via a "../../etc/cron.d/x" archive entry (Zip Slip).
+// This matters because --resolve-remote fetches sources straight out of
+// .tf files, including HTTP/S3/GCS archives that could be compromised or
+// MITM'd in transit.
+func safeJoin(dir, name string) (string, error) {
+	target := filepath.Join(dir, name)
+	rel, err := filepath.Rel(dir, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry %q escapes extraction directory", name)
+	}
+	return target, nil
+}
+
+func writeFile(r io.Reader, target string) error {
+	f, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("failed to create %q: %w", target, err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("failed to write %q: %w", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	goversion "github.com/hashicorp/go-version"
+	"github.com/takaishi/tftargets/sources"
+)
+
+// RegistryClient resolves Terraform Registry module addresses to the
+// underlying VCS/archive URL they are served from, following the Module
+// Registry Protocol's download endpoint:
+// https://developer.hashicorp.com/terraform/internals/module-registry-protocol
+type RegistryClient struct {
+	HTTPClient *http.Client
+}
+
+// NewRegistryClient returns a RegistryClient using http.DefaultClient.
+func NewRegistryClient() *RegistryClient {
+	return &RegistryClient{HTTPClient: http.DefaultClient}
+}
+
+// ResolveDownloadURL asks the module's registry host for the real source
+// location, returned in the "X-Terraform-Get" response header. ref may be a
+// version constraint (e.g. "~>5.0", the common case for a registry module
+// source) rather than an exact version, so it is first resolved against the
+// module's published versions; when ref is empty the registry's latest
+// version is used instead.
+func (c *RegistryClient) ResolveDownloadURL(ctx context.Context, addr *sources.RegistryAddress, ref string) (string, error) {
+	version := ""
+	if ref != "" {
+		resolved, err := c.resolveVersion(ctx, addr, ref)
+		if err != nil {
+			return "", err
+		}
+		version = resolved
+	}
+
+	endpoint := fmt.Sprintf("https://%s/v1/modules/%s/%s/%s/download", addr.Host, addr.Namespace, addr.Name, addr.TargetSystem)
+	if version != "" {
+		endpoint = fmt.Sprintf("https://%s/v1/modules/%s/%s/%s/%s/download", addr.Host, addr.Namespace, addr.Name, addr.TargetSystem, version)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build registry request: %w", err)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach registry %q: %w", addr.Host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("registry %q returned status %d for %s/%s/%s", addr.Host, resp.StatusCode, addr.Namespace, addr.Name, addr.TargetSystem)
+	}
+
+	downloadURL := resp.Header.Get("X-Terraform-Get")
+	if downloadURL == "" {
+		return "", fmt.Errorf("registry %q did not return a download URL for %s/%s/%s", addr.Host, addr.Namespace, addr.Name, addr.TargetSystem)
+	}
+	return downloadURL, nil
+}
+
+// resolveVersion lists addr's published versions on the registry and returns
+// the highest one satisfying constraintStr, since the download endpoint's
+// version path segment only accepts an exact version - never a constraint
+// like "~>5.0", which is what a registry module source is commonly pinned
+// to.
+func (c *RegistryClient) resolveVersion(ctx context.Context, addr *sources.RegistryAddress, constraintStr string) (string, error) {
+	constraint, err := goversion.NewConstraint(constraintStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid version constraint %q for %s/%s/%s: %w", constraintStr, addr.Namespace, addr.Name, addr.TargetSystem, err)
+	}
+
+	endpoint := fmt.Sprintf("https://%s/v1/modules/%s/%s/%s/versions", addr.Host, addr.Namespace, addr.Name, addr.TargetSystem)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build registry versions request: %w", err)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach registry %q: %w", addr.Host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("registry %q returned status %d listing versions for %s/%s/%s", addr.Host, resp.StatusCode, addr.Namespace, addr.Name, addr.TargetSystem)
+	}
+
+	var payload struct {
+		Modules []struct {
+			Versions []struct {
+				Version string `json:"version"`
+			} `json:"versions"`
+		} `json:"modules"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", fmt.Errorf("failed to parse registry versions response: %w", err)
+	}
+	if len(payload.Modules) == 0 {
+		return "", fmt.Errorf("registry %q returned no versions for %s/%s/%s", addr.Host, addr.Namespace, addr.Name, addr.TargetSystem)
+	}
+
+	var best *goversion.Version
+	var bestRaw string
+	for _, v := range payload.Modules[0].Versions {
+		parsed, err := goversion.NewVersion(v.Version)
+		if err != nil {
+			continue
+		}
+		if !constraint.Check(parsed) {
+			continue
+		}
+		if best == nil || parsed.GreaterThan(best) {
+			best = parsed
+			bestRaw = v.Version
+		}
+	}
+	if best == nil {
+		return "", fmt.Errorf("no published version of %s/%s/%s satisfies constraint %q", addr.Namespace, addr.Name, addr.TargetSystem, constraintStr)
+	}
+	return bestRaw, nil
+}
@@ -0,0 +1,260 @@
+// Package resolver fetches non-local Terraform module sources into a local
+// cache so their contents can be inspected like any local module. It is used
+// when tftargets is run with --resolve-remote, since by default a changed
+// ref on a pinned Git/registry module produces no visible file change.
+package resolver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/takaishi/tftargets/sources"
+)
+
+// Resolver fetches non-local module sources into CacheDir, keyed by a hash
+// of the source and the ref/version it was fetched at, and reports whether
+// the ref changed compared to the last time it was resolved.
+type Resolver struct {
+	CacheDir string
+	Registry *RegistryClient
+}
+
+// New creates a Resolver backed by cacheDir, creating it if necessary.
+func New(cacheDir string) (*Resolver, error) {
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create resolver cache dir: %w", err)
+	}
+	return &Resolver{CacheDir: cacheDir, Registry: NewRegistryClient()}, nil
+}
+
+// Result is the outcome of resolving a single module source.
+type Result struct {
+	// Dir is the local directory the source was fetched into.
+	Dir string
+	// RefChanged is true when the ref/version resolved this run differs
+	// from the ref recorded the last time this source was resolved.
+	RefChanged bool
+}
+
+// cacheKey derives a stable, filesystem-safe directory name from a source
+// address and the ref it is pinned to.
+func cacheKey(addr *sources.Address) string {
+	h := sha256.Sum256([]byte(addr.Raw))
+	return hex.EncodeToString(h[:])
+}
+
+// Resolve fetches addr into the cache, reusing a prior clone when present,
+// and reports whether its ref changed since the last resolution.
+func (r *Resolver) Resolve(ctx context.Context, addr *sources.Address) (*Result, error) {
+	key := cacheKey(addr)
+	dir := filepath.Join(r.CacheDir, key)
+
+	transport := addr.Transport
+	if addr.Type == sources.TypeRegistry {
+		downloadURL, err := r.Registry.ResolveDownloadURL(ctx, addr.Registry, addr.Ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve registry module %q: %w", addr.Raw, err)
+		}
+		transport = downloadURL
+	}
+
+	ref := addr.Ref
+	if addr.Type == sources.TypeRegistry {
+		// addr.Ref is a version constraint that ResolveDownloadURL has
+		// already resolved; the exact ref to clone comes from the download
+		// URL below.
+		ref = ""
+	}
+	subdir := addr.Subdir
+
+	var resolvedRev string
+
+	switch addr.Type {
+	case sources.TypeGit, sources.TypeGitHub, sources.TypeRegistry:
+		normalized, transportRef, transportSubdir, err := normalizeGitTransport(transport)
+		if err != nil {
+			return nil, err
+		}
+		transport = normalized
+		if ref == "" {
+			ref = transportRef
+		}
+		if subdir == "" {
+			subdir = transportSubdir
+		}
+		if err := cloneOrUpdateGit(ctx, transport, ref, dir); err != nil {
+			return nil, err
+		}
+		resolvedRev, err = gitRevParseHEAD(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+	case sources.TypeMercurial:
+		if err := cloneOrUpdateHg(ctx, transport, ref, dir); err != nil {
+			return nil, err
+		}
+		rev, err := hgID(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+		resolvedRev = rev
+	case sources.TypeHTTP, sources.TypeS3, sources.TypeGCS:
+		if err := fetchArchive(ctx, transport, dir); err != nil {
+			return nil, err
+		}
+		// An archive source has no revision of its own to track: its
+		// identity is the URL baked into the cache key, so a moved ref
+		// is already a cache miss rather than something recordRef needs
+		// to detect.
+		resolvedRev = ref
+	default:
+		return nil, fmt.Errorf("resolver: unsupported source type %q for %q", addr.Type, addr.Raw)
+	}
+
+	refChanged, err := recordRef(dir, resolvedRev)
+	if err != nil {
+		return nil, err
+	}
+
+	moduleDir := dir
+	if subdir != "" {
+		moduleDir = filepath.Join(dir, subdir)
+	}
+
+	slog.Debug("resolved remote module", "source", addr.Raw, "dir", moduleDir, "refChanged", refChanged)
+	return &Result{Dir: moduleDir, RefChanged: refChanged}, nil
+}
+
+// refMarkerFile is the name of the file within a cache entry that records
+// the revision it was last resolved at, so a subsequent run can detect that
+// a pinned floating ref (e.g. "main", or a registry version constraint)
+// moved even though no local file changed.
+const refMarkerFile = ".tftargets-ref"
+
+// recordRef compares rev - the actual commit/changeset resolved this run,
+// not the literal ref/constraint string, which doesn't change just because
+// what it points at did - against the value recorded in dir from a previous
+// resolution, then overwrites it with rev.
+func recordRef(dir, rev string) (changed bool, err error) {
+	path := filepath.Join(dir, refMarkerFile)
+	prev, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read ref marker: %w", err)
+	}
+	changed = err == nil && string(prev) != rev
+	if err := os.WriteFile(path, []byte(rev), 0o644); err != nil {
+		return false, fmt.Errorf("failed to write ref marker: %w", err)
+	}
+	return changed, nil
+}
+
+// gitRevParseHEAD returns the commit SHA that dir's checkout currently has
+// checked out.
+func gitRevParseHEAD(ctx context.Context, dir string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse HEAD failed in %q: %w", dir, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// hgID returns the changeset hash dir's checkout currently has updated to.
+func hgID(ctx context.Context, dir string) (string, error) {
+	cmd := exec.CommandContext(ctx, "hg", "id", "-i")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("hg id failed in %q: %w", dir, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// normalizeGitTransport rewrites transport into a URL "git clone" can
+// consume directly. transport may still be a go-getter-shorthand address at
+// this point - a forced getter prefix, subdir and query string it carried as
+// a module's literal source, or a registry's X-Terraform-Get response, which
+// is commonly itself a bare "github.com/org/repo" shorthand rather than a
+// real clone URL. It is re-run through sources.Parse to strip those, and a
+// bare GitHub shorthand is rewritten to its https:// clone URL.
+func normalizeGitTransport(transport string) (url, ref, subdir string, err error) {
+	addr, err := sources.Parse(transport)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to parse resolved transport %q: %w", transport, err)
+	}
+
+	url = addr.Transport
+	if addr.Type == sources.TypeGitHub {
+		url = "https://" + strings.TrimSuffix(url, ".git") + ".git"
+	}
+	return url, addr.Ref, addr.Subdir, nil
+}
+
+func cloneOrUpdateGit(ctx context.Context, transport, ref, dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		args := []string{"clone", "--depth=1"}
+		if ref != "" {
+			args = append(args, "--branch", ref)
+		}
+		args = append(args, transport, dir)
+		return runCmd(ctx, "", "git", args...)
+	}
+
+	if err := runCmd(ctx, dir, "git", "fetch", "--depth=1", "origin", refOrDefault(ref)); err != nil {
+		return err
+	}
+	return runCmd(ctx, dir, "git", "checkout", "FETCH_HEAD")
+}
+
+func cloneOrUpdateHg(ctx context.Context, transport, ref, dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		args := []string{"clone"}
+		if ref != "" {
+			args = append(args, "--rev", ref)
+		}
+		args = append(args, transport, dir)
+		return runCmd(ctx, "", "hg", args...)
+	}
+	args := []string{"pull"}
+	if ref != "" {
+		args = append(args, "--rev", ref)
+	}
+	if err := runCmd(ctx, dir, "hg", args...); err != nil {
+		return err
+	}
+	return runCmd(ctx, dir, "hg", "update", refOrDefault(ref))
+}
+
+// fetchArchive downloads an HTTP(S)/S3/GCS archive source into dir. S3 and
+// GCS getter URLs are expected to already have been rewritten to a plain
+// HTTPS URL by the time they reach the resolver.
+func fetchArchive(ctx context.Context, transport, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create archive cache dir: %w", err)
+	}
+	return downloadArchive(ctx, transport, dir)
+}
+
+func refOrDefault(ref string) string {
+	if ref == "" {
+		return "HEAD"
+	}
+	return ref
+}
+
+func runCmd(ctx context.Context, dir, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s failed: %w: %s", name, args, err, out)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Package sources parses Terraform module "source" strings into a typed
+// representation, similar in spirit to go-getter's detector chain. It keeps
+// the forced getter prefix, transport URL, subdirectory and query parameters
+// intact so callers can make decisions (e.g. remote fetching, ref-based
+// caching) without re-parsing the raw string themselves.
+package sources
+
+import (
+	"net/url"
+	"strings"
+)
+
+// SourceType classifies a parsed module source address.
+type SourceType string
+
+const (
+	TypeLocal     SourceType = "local"
+	TypeRegistry  SourceType = "registry"
+	TypeGit       SourceType = "git"
+	TypeGitHub    SourceType = "github"
+	TypeHTTP      SourceType = "http"
+	TypeS3        SourceType = "s3"
+	TypeGCS       SourceType = "gcs"
+	TypeMercurial SourceType = "mercurial"
+	TypeUnknown   SourceType = "unknown"
+)
+
+const defaultRegistryHost = "registry.terraform.io"
+
+// RegistryAddress is the decomposed form of a Terraform Registry module
+// address, e.g. "terraform-aws-modules/iam/aws" ->
+// {Host: "registry.terraform.io", Namespace: "terraform-aws-modules", Name: "iam", TargetSystem: "aws"}.
+type RegistryAddress struct {
+	Host         string
+	Namespace    string
+	Name         string
+	TargetSystem string
+}
+
+// Address is the parsed, structural form of a module source string.
+type Address struct {
+	// Raw is the original, unmodified source string.
+	Raw string
+
+	// Type is the classified kind of source.
+	Type SourceType
+
+	// ForcedGetter is the "git", "hg", "s3" or "gcs" prefix explicitly
+	// requested via "<getter>::", empty if the source had none.
+	ForcedGetter string
+
+	// Transport is the remaining URL-like string after stripping the
+	// forced getter prefix, subdir and query string. Empty for local and
+	// registry sources.
+	Transport string
+
+	// Subdir is the portion of the source after "//", if any.
+	Subdir string
+
+	// Query holds the parsed "?key=value" parameters, notably "ref" and
+	// "version".
+	Query url.Values
+
+	// Ref is a convenience accessor for Query.Get("ref"), falling back to
+	// Query.Get("version") (the form registry addresses use) when "ref" is
+	// absent, whatever the source type.
+	Ref string
+
+	// Local is the relative path for TypeLocal sources.
+	Local string
+
+	// Registry is populated for TypeRegistry sources.
+	Registry *RegistryAddress
+}
+
+// Parse splits a Terraform module source string into an Address using the
+// same detector-table approach as go-getter: strip the forced getter prefix,
+// split off the subdirectory and query string, then classify what remains.
+func Parse(source string) (*Address, error) {
+	addr := &Address{Raw: source}
+
+	rest, forcedGetter := splitForcedGetter(source)
+	addr.ForcedGetter = forcedGetter
+
+	rest, query, err := splitQuery(rest)
+	if err != nil {
+		return nil, err
+	}
+	addr.Query = query
+	addr.Ref = query.Get("ref")
+	if addr.Ref == "" {
+		addr.Ref = query.Get("version")
+	}
+
+	rest, subdir := splitSubdir(rest)
+	addr.Subdir = subdir
+
+	sourceType, registry := classify(rest, forcedGetter)
+	addr.Type = sourceType
+
+	switch sourceType {
+	case TypeLocal:
+		addr.Local = rest
+	case TypeRegistry:
+		addr.Registry = registry
+	default:
+		addr.Transport = rest
+	}
+
+	return addr, nil
+}
+
+// splitForcedGetter strips a leading "<getter>::" prefix such as "git::" or
+// "s3::" and returns the getter name alongside the remainder.
+func splitForcedGetter(source string) (rest string, getter string) {
+	idx := strings.Index(source, "::")
+	if idx < 0 {
+		return source, ""
+	}
+	// Guard against "https://" style schemes being mistaken for a forced
+	// getter prefix: a real forced getter never contains "/" before "::".
+	if strings.ContainsAny(source[:idx], "/:") {
+		return source, ""
+	}
+	return source[idx+2:], source[:idx]
+}
+
+// splitSubdir splits a module source on the first "//" that is not part of
+// a "scheme://" separator.
+func splitSubdir(source string) (rest string, subdir string) {
+	schemeEnd := 0
+	if i := strings.Index(source, "://"); i >= 0 {
+		schemeEnd = i + 3
+	}
+
+	idx := strings.Index(source[schemeEnd:], "//")
+	if idx < 0 {
+		return source, ""
+	}
+	idx += schemeEnd
+	return source[:idx], source[idx+2:]
+}
+
+// splitQuery splits off and parses a trailing "?key=value&..." query string,
+// which may be attached to either the main source or its subdir.
+func splitQuery(source string) (rest string, query url.Values, err error) {
+	idx := strings.Index(source, "?")
+	if idx < 0 {
+		return source, url.Values{}, nil
+	}
+	query, err = url.ParseQuery(source[idx+1:])
+	if err != nil {
+		return source, nil, err
+	}
+	return source[:idx], query, nil
+}
@@ -0,0 +1,168 @@
+package sources
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		wantType   SourceType
+		wantLocal  string
+		wantTrans  string
+		wantSubdir string
+		wantRef    string
+		wantReg    *RegistryAddress
+	}{
+		{
+			name:      "local relative path",
+			source:    "./modules/vpc",
+			wantType:  TypeLocal,
+			wantLocal: "./modules/vpc",
+		},
+		{
+			name:      "local absolute path",
+			source:    "/abs/modules/vpc",
+			wantType:  TypeLocal,
+			wantLocal: "/abs/modules/vpc",
+		},
+		{
+			name:     "registry address, default host",
+			source:   "terraform-aws-modules/vpc/aws",
+			wantType: TypeRegistry,
+			wantReg:  &RegistryAddress{Host: defaultRegistryHost, Namespace: "terraform-aws-modules", Name: "vpc", TargetSystem: "aws"},
+		},
+		{
+			name:     "registry address, explicit host",
+			source:   "app.terraform.io/example-corp/vpc/aws",
+			wantType: TypeRegistry,
+			wantReg:  &RegistryAddress{Host: "app.terraform.io", Namespace: "example-corp", Name: "vpc", TargetSystem: "aws"},
+		},
+		{
+			name:       "registry address with subdir and version constraint",
+			source:     "terraform-aws-modules/iam/aws//modules/iam-account?version=~>5.0",
+			wantType:   TypeRegistry,
+			wantSubdir: "modules/iam-account",
+			wantRef:    "~>5.0",
+			wantReg:    &RegistryAddress{Host: defaultRegistryHost, Namespace: "terraform-aws-modules", Name: "iam", TargetSystem: "aws"},
+		},
+		{
+			name:      "forced git getter over https",
+			source:    "git::https://example.com/org/repo.git?ref=v1.0.0",
+			wantType:  TypeGit,
+			wantTrans: "https://example.com/org/repo.git",
+			wantRef:   "v1.0.0",
+		},
+		{
+			name:      "git ssh shorthand",
+			source:    "git@github.com:org/repo.git",
+			wantType:  TypeGit,
+			wantTrans: "git@github.com:org/repo.git",
+		},
+		{
+			name:      "github shorthand",
+			source:    "github.com/org/repo",
+			wantType:  TypeGitHub,
+			wantTrans: "github.com/org/repo",
+		},
+		{
+			name:       "github shorthand with subdir and ref",
+			source:     "github.com/org/repo//modules/vpc?ref=v2.0.0",
+			wantType:   TypeGitHub,
+			wantTrans:  "github.com/org/repo",
+			wantSubdir: "modules/vpc",
+			wantRef:    "v2.0.0",
+		},
+		{
+			name:      "http archive",
+			source:    "https://example.com/archive.tar.gz",
+			wantType:  TypeHTTP,
+			wantTrans: "https://example.com/archive.tar.gz",
+		},
+		{
+			name:      "forced hg getter",
+			source:    "hg::https://example.com/org/repo?ref=default",
+			wantType:  TypeMercurial,
+			wantTrans: "https://example.com/org/repo",
+			wantRef:   "default",
+		},
+		{
+			name:      "forced s3 getter",
+			source:    "s3::https://s3.amazonaws.com/bucket/path/module.zip",
+			wantType:  TypeS3,
+			wantTrans: "https://s3.amazonaws.com/bucket/path/module.zip",
+		},
+		{
+			name:       "scheme:// is not mistaken for a subdir separator",
+			source:     "https://example.com/foo//bar",
+			wantType:   TypeHTTP,
+			wantTrans:  "https://example.com/foo",
+			wantSubdir: "bar",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, err := Parse(tt.source)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tt.source, err)
+			}
+
+			if addr.Type != tt.wantType {
+				t.Errorf("Type = %q, want %q", addr.Type, tt.wantType)
+			}
+			if addr.Local != tt.wantLocal {
+				t.Errorf("Local = %q, want %q", addr.Local, tt.wantLocal)
+			}
+			if addr.Transport != tt.wantTrans {
+				t.Errorf("Transport = %q, want %q", addr.Transport, tt.wantTrans)
+			}
+			if addr.Subdir != tt.wantSubdir {
+				t.Errorf("Subdir = %q, want %q", addr.Subdir, tt.wantSubdir)
+			}
+			if addr.Ref != tt.wantRef {
+				t.Errorf("Ref = %q, want %q", addr.Ref, tt.wantRef)
+			}
+			if tt.wantReg != nil && !reflect.DeepEqual(addr.Registry, tt.wantReg) {
+				t.Errorf("Registry = %+v, want %+v", addr.Registry, tt.wantReg)
+			}
+		})
+	}
+}
+
+func TestSplitSubdirDistinguishesSchemeSeparator(t *testing.T) {
+	rest, subdir := splitSubdir("https://example.com/foo//bar")
+	if rest != "https://example.com/foo" || subdir != "bar" {
+		t.Fatalf("splitSubdir = (%q, %q), want (%q, %q)", rest, subdir, "https://example.com/foo", "bar")
+	}
+
+	rest, subdir = splitSubdir("https://example.com/foo")
+	if rest != "https://example.com/foo" || subdir != "" {
+		t.Fatalf("splitSubdir = (%q, %q), want no subdir split", rest, subdir)
+	}
+}
+
+func TestSplitForcedGetterPrecedence(t *testing.T) {
+	rest, getter := splitForcedGetter("git::https://example.com/org/repo.git")
+	if getter != "git" || rest != "https://example.com/org/repo.git" {
+		t.Fatalf("splitForcedGetter = (%q, %q), want (%q, %q)", rest, getter, "https://example.com/org/repo.git", "git")
+	}
+
+	// A "scheme://" should never be mistaken for a forced getter prefix.
+	rest, getter = splitForcedGetter("https://example.com/org/repo.git")
+	if getter != "" || rest != "https://example.com/org/repo.git" {
+		t.Fatalf("splitForcedGetter = (%q, %q), want no forced getter detected", rest, getter)
+	}
+}
+
+func TestParseRefFallsBackToVersionQueryParam(t *testing.T) {
+	addr, err := Parse("terraform-aws-modules/vpc/aws?version=5.8.1")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if addr.Ref != "5.8.1" {
+		t.Fatalf("Ref = %q, want %q", addr.Ref, "5.8.1")
+	}
+}
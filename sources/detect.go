@@ -0,0 +1,106 @@
+package sources
+
+import (
+	"regexp"
+	"strings"
+)
+
+// registryPattern matches a Terraform Registry module address, optionally
+// prefixed with a non-default host, e.g. "namespace/name/provider" or
+// "example.com/namespace/name/provider".
+var registryPattern = regexp.MustCompile(`^([a-zA-Z0-9.-]+/)?[a-zA-Z0-9_-]+/[a-zA-Z0-9_-]+/[a-zA-Z0-9_-]+$`)
+
+// forcedGetterTypes maps an explicit "<getter>::" prefix to its SourceType.
+var forcedGetterTypes = map[string]SourceType{
+	"git": TypeGit,
+	"hg":  TypeMercurial,
+	"s3":  TypeS3,
+	"gcs": TypeGCS,
+}
+
+// detector is a single rule in the table-based classifier, modeled after
+// go-getter's detector chain: each entry inspects the (forced-getter-less,
+// subdir-less, query-less) source string and, if it matches, returns the
+// classified type. New schemes are added by appending to detectors rather
+// than editing call sites.
+type detector struct {
+	name   string
+	detect func(source string) bool
+	typ    SourceType
+}
+
+var detectors = []detector{
+	{
+		name:   "local relative path",
+		detect: func(s string) bool { return strings.HasPrefix(s, "./") || strings.HasPrefix(s, "../") },
+		typ:    TypeLocal,
+	},
+	{
+		name:   "local absolute path",
+		detect: func(s string) bool { return strings.HasPrefix(s, "/") },
+		typ:    TypeLocal,
+	},
+	{
+		name:   "http(s) URL",
+		detect: func(s string) bool { return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") },
+		typ:    TypeHTTP,
+	},
+	{
+		name:   "github shorthand",
+		detect: func(s string) bool { return strings.HasPrefix(s, "github.com/") },
+		typ:    TypeGitHub,
+	},
+	{
+		name:   "git ssh shorthand",
+		detect: func(s string) bool { return strings.HasPrefix(s, "git@") },
+		typ:    TypeGit,
+	},
+	{
+		name:   "registry address",
+		detect: registryPattern.MatchString,
+		typ:    TypeRegistry,
+	},
+}
+
+// classify determines the SourceType of rest (the source string with its
+// forced getter, subdir and query already stripped). When a forced getter
+// prefix was present it always wins, matching go-getter's precedence rules.
+// For TypeRegistry it also returns the decomposed RegistryAddress.
+func classify(rest string, forcedGetter string) (SourceType, *RegistryAddress) {
+	if typ, ok := forcedGetterTypes[forcedGetter]; ok {
+		return typ, nil
+	}
+
+	for _, d := range detectors {
+		if !d.detect(rest) {
+			continue
+		}
+		if d.typ == TypeRegistry {
+			return TypeRegistry, parseRegistryAddress(rest)
+		}
+		return d.typ, nil
+	}
+
+	return TypeUnknown, nil
+}
+
+// parseRegistryAddress splits a validated registry address into its host,
+// namespace, name and target system (provider) parts, filling in the
+// default registry host when none was given.
+func parseRegistryAddress(source string) *RegistryAddress {
+	parts := strings.Split(source, "/")
+	if len(parts) == 4 {
+		return &RegistryAddress{
+			Host:         parts[0],
+			Namespace:    parts[1],
+			Name:         parts[2],
+			TargetSystem: parts[3],
+		}
+	}
+	return &RegistryAddress{
+		Host:         defaultRegistryHost,
+		Namespace:    parts[0],
+		Name:         parts[1],
+		TargetSystem: parts[2],
+	}
+}
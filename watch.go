@@ -0,0 +1,166 @@
+package tftargets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/takaishi/tftargets/resolver"
+)
+
+// WatchCmd starts a debounced filesystem watch over SearchPath and streams
+// the live target set as newline-delimited JSON, so editor plugins and CI
+// runners can reuse one long-lived process instead of re-parsing the whole
+// tree on every commit.
+type WatchCmd struct {
+	Debounce time.Duration `help:"Debounce window for coalescing bursts of filesystem events" default:"200ms"`
+}
+
+func (w *WatchCmd) Run(app *App, ctx context.Context) error {
+	return app.watch(ctx, w.Debounce)
+}
+
+// ignoredWatchDirs are directory names whose contents are never part of a
+// hand-authored module and should not be watched or trigger recomputation.
+var ignoredWatchDirs = []string{".terragrunt-cache", ".terraform"}
+
+func shouldIgnoreWatchDir(path string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if Contains(ignoredWatchDirs, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// addWatchRecursive walks root and adds every non-ignored directory to
+// watcher, since fsnotify only watches the directories you explicitly add.
+func addWatchRecursive(watcher *fsnotify.Watcher, root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if shouldIgnoreWatchDir(path) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+func (app *App) watch(ctx context.Context, debounce time.Duration) error {
+	searchPath := filepath.Join(app.CLI.BaseDir, app.CLI.SearchPath)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start filesystem watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := addWatchRecursive(watcher, searchPath); err != nil {
+		return fmt.Errorf("failed to watch %q: %w", searchPath, err)
+	}
+
+	pending := make(Set[string])
+	var debounceTimer *time.Timer
+	var timerC <-chan time.Time
+
+	resetTimer := func() {
+		if debounceTimer == nil {
+			debounceTimer = time.NewTimer(debounce)
+		} else {
+			if !debounceTimer.Stop() {
+				select {
+				case <-debounceTimer.C:
+				default:
+				}
+			}
+			debounceTimer.Reset(debounce)
+		}
+		timerC = debounceTimer.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if shouldIgnoreWatchDir(event.Name) {
+				continue
+			}
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := addWatchRecursive(watcher, event.Name); err != nil {
+						slog.Error("failed to watch new directory", "dir", event.Name, "error", err)
+					}
+				}
+			}
+			app.moduleManager.Invalidate(filepath.Dir(event.Name))
+			pending.Add(event.Name)
+			resetTimer()
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Error("filesystem watcher error", "error", err)
+
+		case <-timerC:
+			timerC = nil
+			changed := pending.ToSlice()
+			pending = make(Set[string])
+
+			if err := app.emitTargets(ctx, searchPath, changed); err != nil {
+				slog.Error("failed to recompute targets", "error", err)
+			}
+		}
+	}
+}
+
+// emitTargets recomputes the candidate -> module map for searchPath and
+// prints the set of candidates affected by changed as one JSON line.
+func (app *App) emitTargets(ctx context.Context, searchPath string, changed []string) error {
+	var res *resolver.Resolver
+	if app.CLI.ResolveRemote {
+		var err error
+		res, err = resolver.New(app.CLI.ResolverCacheDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	targetCandidates, err := findTargetCandidates(searchPath)
+	if err != nil {
+		return err
+	}
+
+	candidateModules := make(map[string]Set[string])
+	for _, candidate := range targetCandidates {
+		calls, _, err := app.getModuleCalls(ctx, candidate, res)
+		if err != nil {
+			return err
+		}
+		candidateModules[candidate] = calls
+	}
+
+	targets := matchTargets(changed, candidateModules)
+
+	jsonOutput, err := json.Marshal(targets.ToSlice())
+	if err != nil {
+		return fmt.Errorf("failed to marshal targets: %w", err)
+	}
+	fmt.Println(string(jsonOutput))
+	return nil
+}